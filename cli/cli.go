@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/bounoable/dragoman"
 	"github.com/bounoable/dragoman/directory"
+	"github.com/bounoable/dragoman/memory"
 	"github.com/bounoable/dragoman/text"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -49,11 +52,13 @@ type CLI struct {
 	// flags
 	translatorArgs     map[string]*string
 	sourceLang         string
-	targetLang         string
+	targetLangs        []string
 	out                string
 	preserve           string
+	glossaryPath       string
 	parallel           int
 	escapeDoubleQuotes bool
+	tmPath             string
 
 	translator *dragoman.Translator
 }
@@ -82,6 +87,34 @@ type Format struct {
 	Flags func(*pflag.FlagSet)
 	// Ranger creates the text ranger for the format.
 	Ranger func() (text.Ranger, error)
+	// DirRanger optionally creates the text ranger for directory-mode
+	// translation, given the absolute path of the directory being
+	// translated, instead of Ranger. Use this when a format can extract
+	// more accurately with whole-directory context (e.g. a Go package
+	// loaded with type information and import resolution) than it can one
+	// file at a time. Falls back to Ranger if unset. Not consulted in
+	// single-file mode.
+	DirRanger func(dir string) (text.Ranger, error)
+	// Options optionally derives extra TranslateOptions from a file's
+	// content (e.g. a dragoman.Preserve() pattern built from the file
+	// itself), in addition to the options derived from the CLI's flags.
+	Options func(content []byte) ([]dragoman.TranslateOption, error)
+	// Preprocess optionally rewrites a file's content before it's handed to
+	// the Ranger and translated, for normalization the format's Ranger
+	// relies on (e.g. seeding a catalog's empty translation fields with
+	// their source text, so there's something at that position to
+	// translate).
+	Preprocess func(content []byte) ([]byte, error)
+	// PostProcess optionally rewrites a file's translated output before
+	// it's written, for metadata that the format's Ranger doesn't
+	// translate itself (e.g. a catalog's top-level language tag).
+	PostProcess func(original, translated []byte, targetLang string) ([]byte, error)
+	// OutputPath optionally overrides where a single-file translation is
+	// written, given the source path and targetLang (e.g. a build-tagged
+	// shadow file next to a Go source file). Returning "" falls back to
+	// the default: cli.out (with targetLang inserted if there's more than
+	// one target language) or stdout. Not consulted in directory mode.
+	OutputPath func(srcPath, targetLang string) string
 }
 
 // Source is a file source configuration.
@@ -136,11 +169,13 @@ func (cli *CLI) init() {
 		}
 
 		cmd.PersistentFlags().StringVar(&cli.sourceLang, "from", "en", "Source language")
-		cmd.PersistentFlags().StringVar(&cli.targetLang, "into", "en", "Target language")
+		cmd.PersistentFlags().StringSliceVar(&cli.targetLangs, "into", []string{"en"}, "Target language(s) (comma-separated, or repeat the flag)")
 		cmd.PersistentFlags().StringVar(&cli.preserve, "preserve", "", "Prevent translation of substrings (regular expression)")
+		cmd.PersistentFlags().StringVar(&cli.glossaryPath, "glossary", "", "Path to a glossary file (.yaml/.yml or .csv) of do-not-translate terms")
 		cmd.PersistentFlags().StringVarP(&cli.out, "out", "o", "", "Write the result to the specified filepath")
 		cmd.PersistentFlags().IntVarP(&cli.parallel, "parallel", "p", 1, "Max concurrent translation requests")
 		cmd.PersistentFlags().BoolVarP(&cli.escapeDoubleQuotes, "escape", "e", false, "Escape double quotes in translation results")
+		cmd.PersistentFlags().StringVar(&cli.tmPath, "tm", "", "Path to a translation memory file, reused across runs")
 
 		if format.Flags != nil {
 			format.Flags(cmd.PersistentFlags())
@@ -230,6 +265,46 @@ func (cli *CLI) sourceCommand(formatCmd *cobra.Command, source Source, format Fo
 	formatCmd.AddCommand(cmd)
 }
 
+// translateOptions builds the dragoman.TranslateOptions shared by single
+// file and directory translation, from the CLI's flags. The returned flush
+// func persists the translation memory (if any) and must be called once
+// translation has finished.
+func (cli *CLI) translateOptions() ([]dragoman.TranslateOption, func() error, error) {
+	opts := []dragoman.TranslateOption{
+		dragoman.Parallel(cli.parallel),
+		dragoman.EscapeDoubleQuotes(cli.escapeDoubleQuotes),
+	}
+
+	if cli.preserve != "" {
+		expr, err := regexp.Compile(cli.preserve)
+		if err != nil {
+			return nil, nil, fmt.Errorf("compile regexp (%v): %w", cli.preserve, err)
+		}
+		opts = append(opts, dragoman.Preserve(expr))
+	}
+
+	if cli.glossaryPath != "" {
+		g, err := dragoman.LoadGlossaryFile(cli.glossaryPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load glossary (%v): %w", cli.glossaryPath, err)
+		}
+		opts = append(opts, dragoman.WithGlossary(g))
+	}
+
+	flush := func() error { return nil }
+
+	if cli.tmPath != "" {
+		tm, err := memory.OpenJSONFile(cli.tmPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open translation memory (%v): %w", cli.tmPath, err)
+		}
+		opts = append(opts, dragoman.WithTranslationMemory(tm))
+		flush = tm.Flush
+	}
+
+	return opts, flush, nil
+}
+
 func (cli *CLI) translateSingleFile(ctx context.Context, format Format, source Source, p string) error {
 	r, err := source.Reader(p)
 	if err != nil {
@@ -239,17 +314,29 @@ func (cli *CLI) translateSingleFile(ctx context.Context, format Format, source S
 		defer c.Close()
 	}
 
-	opts := []dragoman.TranslateOption{
-		dragoman.Parallel(cli.parallel),
-		dragoman.EscapeDoubleQuotes(cli.escapeDoubleQuotes),
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read file %s: %w", p, err)
 	}
 
-	if cli.preserve != "" {
-		expr, err := regexp.Compile(cli.preserve)
+	if format.Preprocess != nil {
+		if content, err = format.Preprocess(content); err != nil {
+			return fmt.Errorf("preprocess %s: %w", p, err)
+		}
+	}
+
+	opts, flush, err := cli.translateOptions()
+	if err != nil {
+		return err
+	}
+	defer flush()
+
+	if format.Options != nil {
+		extra, err := format.Options(content)
 		if err != nil {
-			return fmt.Errorf("compile regexp (%v): %w", cli.preserve, err)
+			return fmt.Errorf("format options: %w", err)
 		}
-		opts = append(opts, dragoman.Preserve(expr))
+		opts = append(opts, extra...)
 	}
 
 	ranger, err := format.Ranger()
@@ -257,39 +344,77 @@ func (cli *CLI) translateSingleFile(ctx context.Context, format Format, source S
 		return fmt.Errorf("make ranger: %w", err)
 	}
 
-	res, err := cli.translator.Translate(
-		ctx,
-		r,
-		cli.sourceLang,
-		cli.targetLang,
-		ranger,
-		opts...,
-	)
-	if err != nil {
-		return fmt.Errorf("translate: %w", err)
-	}
+	for _, targetLang := range cli.targetLangs {
+		res, err := cli.translator.Translate(
+			ctx,
+			bytes.NewReader(content),
+			cli.sourceLang,
+			targetLang,
+			ranger,
+			opts...,
+		)
+		if err != nil {
+			return fmt.Errorf("translate into %s: %w", targetLang, err)
+		}
 
-	out := os.Stdout
-	var f *os.File
+		if format.PostProcess != nil {
+			if res, err = format.PostProcess(content, res, targetLang); err != nil {
+				return fmt.Errorf("post-process %s translation: %w", targetLang, err)
+			}
+		}
 
-	if cli.out != "" {
-		if f, err = os.Create(cli.out); err != nil {
-			return fmt.Errorf("create outfile (%v): %w", cli.out, err)
+		if err = cli.writeSingleFileResult(format, p, targetLang, res); err != nil {
+			return err
 		}
-		out = f
 	}
 
-	if _, err = fmt.Fprint(out, string(res)); err != nil {
-		return fmt.Errorf("write result: %w", err)
+	return nil
+}
+
+// writeSingleFileResult writes the translation result for targetLang to the
+// path format.OutputPath(p, targetLang) returns (if format.OutputPath is set
+// and returns a non-empty path), or otherwise to cli.out (or stdout if
+// unset). If more than one target language was requested, the target
+// language is inserted before the out path's extension, e.g. "out.json" ->
+// "out.de.json".
+func (cli *CLI) writeSingleFileResult(format Format, p, targetLang string, res []byte) error {
+	if format.OutputPath != nil {
+		if outPath := format.OutputPath(p, targetLang); outPath != "" {
+			f, err := os.Create(outPath)
+			if err != nil {
+				return fmt.Errorf("create outfile (%v): %w", outPath, err)
+			}
+			if _, err = fmt.Fprint(f, string(res)); err != nil {
+				return fmt.Errorf("write result: %w", err)
+			}
+			return f.Close()
+		}
 	}
 
-	if f != nil {
-		if err = f.Close(); err != nil {
-			return fmt.Errorf("close outfile: %w", err)
+	if cli.out == "" {
+		if len(cli.targetLangs) > 1 {
+			fmt.Fprintf(os.Stdout, "# %s\n", targetLang)
 		}
+		_, err := fmt.Fprint(os.Stdout, string(res))
+		return err
 	}
 
-	return nil
+	outPath := cli.out
+	if len(cli.targetLangs) > 1 {
+		ext := filepath.Ext(outPath)
+		outPath = strings.TrimSuffix(outPath, ext) + "." + targetLang + ext
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("create outfile (%v): %w", outPath, err)
+	}
+
+	if _, err = fmt.Fprint(f, string(res)); err != nil {
+		return fmt.Errorf("write result: %w", err)
+	}
+
+	return f.Close()
 }
 
 func (cli *CLI) translateDirectory(ctx context.Context, format Format, source Source, relPath string) error {
@@ -333,39 +458,51 @@ func (cli *CLI) translateDirectory(ctx context.Context, format Format, source So
 		}
 	}
 
-	opts := []dragoman.TranslateOption{
-		dragoman.Parallel(cli.parallel),
-		dragoman.EscapeDoubleQuotes(cli.escapeDoubleQuotes),
+	opts, flush, err := cli.translateOptions()
+	if err != nil {
+		return err
 	}
+	defer flush()
 
-	if cli.preserve != "" {
-		expr, err := regexp.Compile(cli.preserve)
-		if err != nil {
-			return fmt.Errorf("compile regexp (%v): %w", cli.preserve, err)
+	var rang text.Ranger
+	if format.DirRanger != nil {
+		if rang, err = format.DirRanger(p); err != nil {
+			return fmt.Errorf("create directory ranger for format %s: %w", format.Name, err)
+		}
+	} else {
+		if rang, err = format.Ranger(); err != nil {
+			return fmt.Errorf("creat ranger for format %s: %w", format.Name, err)
 		}
-		opts = append(opts, dragoman.Preserve(expr))
 	}
 
-	rang, err := format.Ranger()
-	if err != nil {
-		return fmt.Errorf("creat ranger for format %s: %w", format.Name, err)
+	dirOpts := []directory.Option{directory.Ranger(format.Ext, rang)}
+	if format.Options != nil {
+		dirOpts = append(dirOpts, directory.ContentOptions(format.Ext, format.Options))
 	}
-	dir := directory.New(p, directory.Ranger(format.Ext, rang))
-
-	res, err := dir.Translate(ctx, cli.translator, cli.sourceLang, cli.targetLang, opts...)
-	if err != nil {
-		return fmt.Errorf("translate directory: %w", err)
+	if format.Preprocess != nil {
+		dirOpts = append(dirOpts, directory.Preprocess(format.Ext, format.Preprocess))
 	}
-
-	if cli.out == "" {
-		printDirectoryResult(dir, res)
-		return nil
+	if format.PostProcess != nil {
+		dirOpts = append(dirOpts, directory.PostProcess(format.Ext, format.PostProcess))
 	}
+	dir := directory.New(p, dirOpts...)
 
-	outDir := directory.New(outPath)
-	for p, s := range res {
-		if err = writeDirectoryResult(outDir.Absolute(p), s); err != nil {
-			return fmt.Errorf("write result: %w", err)
+	for _, targetLang := range cli.targetLangs {
+		res, err := dir.Translate(ctx, cli.translator, cli.sourceLang, targetLang, opts...)
+		if err != nil {
+			return fmt.Errorf("translate directory into %s: %w", targetLang, err)
+		}
+
+		if cli.out == "" {
+			printDirectoryResult(dir, targetLang, res)
+			continue
+		}
+
+		outDir := directory.New(filepath.Join(outPath, targetLang))
+		for p, s := range res {
+			if err = writeDirectoryResult(outDir.Absolute(p), s); err != nil {
+				return fmt.Errorf("write result: %w", err)
+			}
 		}
 	}
 
@@ -422,9 +559,9 @@ func exists(p string) (bool, error) {
 	return true, nil
 }
 
-func printDirectoryResult(dir directory.Dir, res map[string]string) {
+func printDirectoryResult(dir directory.Dir, targetLang string, res map[string]string) {
 	for p, s := range res {
-		fmt.Fprintf(os.Stdout, "# %s\n", dir.Absolute(p))
+		fmt.Fprintf(os.Stdout, "# [%s] %s\n", targetLang, dir.Absolute(p))
 		fmt.Fprint(os.Stdout, s)
 		fmt.Fprint(os.Stdout, "\n")
 	}
@@ -0,0 +1,184 @@
+// Package directory translates every matching file of a directory tree.
+package directory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bounoable/dragoman"
+	"github.com/bounoable/dragoman/text"
+)
+
+// ContentOptionsFunc optionally derives extra TranslateOptions from a
+// file's content (e.g. a dragoman.Preserve() pattern built from the file
+// itself).
+type ContentOptionsFunc func(content []byte) ([]dragoman.TranslateOption, error)
+
+// PreprocessFunc optionally rewrites a file's content before it's handed to
+// its Ranger and translated, for normalization the Ranger relies on (e.g.
+// seeding a catalog's empty translation fields with their source text).
+type PreprocessFunc func(content []byte) ([]byte, error)
+
+// PostProcessFunc optionally rewrites a file's translated output before
+// it's written, for metadata that the file's Ranger doesn't translate
+// itself (e.g. a catalog's top-level language tag).
+type PostProcessFunc func(original, translated []byte, targetLang string) ([]byte, error)
+
+// Dir is a directory that can be translated file by file.
+type Dir struct {
+	path        string
+	rangers     map[string]text.Ranger // keyed by file extension
+	contentOpts map[string]ContentOptionsFunc
+	preprocess  map[string]PreprocessFunc
+	postProcess map[string]PostProcessFunc
+}
+
+// Option is a Dir option.
+type Option func(*Dir)
+
+// New returns a new Dir, rooted at path.
+func New(path string, opts ...Option) Dir {
+	dir := Dir{
+		path:        path,
+		rangers:     make(map[string]text.Ranger),
+		contentOpts: make(map[string]ContentOptionsFunc),
+		preprocess:  make(map[string]PreprocessFunc),
+		postProcess: make(map[string]PostProcessFunc),
+	}
+	for _, opt := range opts {
+		opt(&dir)
+	}
+	return dir
+}
+
+// Ranger registers r as the text.Ranger for files with the given extension
+// (including the leading dot, e.g. ".json").
+func Ranger(ext string, r text.Ranger) Option {
+	return func(dir *Dir) {
+		dir.rangers[ext] = r
+	}
+}
+
+// ContentOptions registers fn to derive extra TranslateOptions for files
+// with the given extension, in addition to the options passed to
+// (Dir).Translate.
+func ContentOptions(ext string, fn ContentOptionsFunc) Option {
+	return func(dir *Dir) {
+		dir.contentOpts[ext] = fn
+	}
+}
+
+// Preprocess registers fn to rewrite the content of files with the given
+// extension before they're translated.
+func Preprocess(ext string, fn PreprocessFunc) Option {
+	return func(dir *Dir) {
+		dir.preprocess[ext] = fn
+	}
+}
+
+// PostProcess registers fn to rewrite the translated output of files with
+// the given extension, before it's returned by (Dir).Translate.
+func PostProcess(ext string, fn PostProcessFunc) Option {
+	return func(dir *Dir) {
+		dir.postProcess[ext] = fn
+	}
+}
+
+// rangerFor returns the text.Ranger and its registered extension for the
+// longest registered extension that is a suffix of p, so that multi-dot
+// extensions (e.g. ".gotext.json") take priority over the plain
+// filepath.Ext() (".json").
+func (dir Dir) rangerFor(p string) (ranger text.Ranger, ext string, ok bool) {
+	name := strings.ToLower(p)
+
+	for e, r := range dir.rangers {
+		if !strings.HasSuffix(name, strings.ToLower(e)) {
+			continue
+		}
+		if len(e) > len(ext) {
+			ext, ranger = e, r
+		}
+	}
+
+	return ranger, ext, ext != ""
+}
+
+// Absolute returns the absolute path of p, relative to the Dir's root.
+func (dir Dir) Absolute(p string) string {
+	return filepath.Join(dir.path, p)
+}
+
+// Translate translates every file in the Dir that has a registered Ranger
+// for its extension, from sourceLang into targetLang. The returned map is
+// keyed by the file's path, relative to the Dir's root.
+func (dir Dir) Translate(
+	ctx context.Context,
+	t *dragoman.Translator,
+	sourceLang, targetLang string,
+	opts ...dragoman.TranslateOption,
+) (map[string]string, error) {
+	results := make(map[string]string)
+
+	err := filepath.Walk(dir.path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		ranger, ext, ok := dir.rangerFor(p)
+		if !ok {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir.path, p)
+		if err != nil {
+			return fmt.Errorf("relative path of %s: %w", p, err)
+		}
+
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", p, err)
+		}
+
+		if fn, ok := dir.preprocess[ext]; ok {
+			if content, err = fn(content); err != nil {
+				return fmt.Errorf("preprocess %s: %w", rel, err)
+			}
+		}
+
+		fileOpts := opts
+		if fn, ok := dir.contentOpts[ext]; ok {
+			extra, err := fn(content)
+			if err != nil {
+				return fmt.Errorf("content options for %s: %w", rel, err)
+			}
+			fileOpts = append(append([]dragoman.TranslateOption{}, opts...), extra...)
+		}
+
+		out, err := t.Translate(ctx, bytes.NewReader(content), sourceLang, targetLang, ranger, fileOpts...)
+		if err != nil {
+			return fmt.Errorf("translate %s: %w", rel, err)
+		}
+
+		if fn, ok := dir.postProcess[ext]; ok {
+			if out, err = fn(content, out, targetLang); err != nil {
+				return fmt.Errorf("post-process %s: %w", rel, err)
+			}
+		}
+
+		results[rel] = string(out)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", dir.path, err)
+	}
+
+	return results, nil
+}
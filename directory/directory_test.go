@@ -0,0 +1,56 @@
+package directory
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/bounoable/dragoman/text"
+)
+
+// stubRanger is a text.Ranger that never actually runs; it only exists so
+// tests can assert on ranger identity.
+type stubRanger struct{ name string }
+
+func (stubRanger) Ranges(context.Context, io.Reader) (<-chan text.Range, <-chan error) {
+	ranges := make(chan text.Range)
+	errs := make(chan error)
+	close(ranges)
+	close(errs)
+	return ranges, errs
+}
+
+func TestRangerForPrefersLongestRegisteredSuffix(t *testing.T) {
+	jsonRanger := stubRanger{name: "json"}
+	gotextRanger := stubRanger{name: "gotext"}
+
+	dir := New("/root",
+		Ranger(".json", jsonRanger),
+		Ranger(".gotext.json", gotextRanger),
+	)
+
+	tests := []struct {
+		path string
+		want stubRanger
+		ok   bool
+	}{
+		{"/root/messages.gotext.json", gotextRanger, true},
+		{"/root/a/b/OTHER.GOTEXT.JSON", gotextRanger, true},
+		{"/root/config.json", jsonRanger, true},
+		{"/root/readme.md", stubRanger{}, false},
+	}
+
+	for _, tt := range tests {
+		r, _, ok := dir.rangerFor(tt.path)
+		if ok != tt.ok {
+			t.Errorf("rangerFor(%q) ok = %v, want %v", tt.path, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if r.(stubRanger) != tt.want {
+			t.Errorf("rangerFor(%q) = %v, want %v", tt.path, r, tt.want)
+		}
+	}
+}
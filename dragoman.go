@@ -0,0 +1,244 @@
+// Package dragoman translates structured documents (JSON, YAML, source
+// code, ...), while preserving everything that isn't meant to be
+// translated.
+package dragoman
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bounoable/dragoman/text"
+)
+
+// Service is a translation service (DeepL, Google Translate, ...).
+type Service interface {
+	// Translate translates text from sourceLang into targetLang.
+	Translate(ctx context.Context, text, sourceLang, targetLang string) (string, error)
+}
+
+// Translator translates structured documents.
+//
+// It extracts the translatable ranges of a document with a text.Ranger,
+// sends them to a Service and stitches the result back together with
+// text.ReplaceMany.
+type Translator struct {
+	svc Service
+}
+
+// New returns a new *Translator that dispatches translation requests to svc.
+func New(svc Service) *Translator {
+	return &Translator{svc: svc}
+}
+
+// TranslateOption is an option for (*Translator).Translate.
+type TranslateOption func(*translateConfig)
+
+type translateConfig struct {
+	parallel           int
+	preserve           *regexp.Regexp
+	escapeDoubleQuotes bool
+	memory             TranslationMemory
+	glossary           Glossary
+}
+
+// Parallel sets the number of concurrent translation requests. n is clamped
+// to a minimum of 1.
+func Parallel(n int) TranslateOption {
+	return func(cfg *translateConfig) {
+		if n < 1 {
+			n = 1
+		}
+		cfg.parallel = n
+	}
+}
+
+// Preserve prevents substrings that match expr from being sent to the
+// translation Service; they are left untouched in the output.
+func Preserve(expr *regexp.Regexp) TranslateOption {
+	return func(cfg *translateConfig) {
+		cfg.preserve = expr
+	}
+}
+
+// EscapeDoubleQuotes escapes double quotes (") in translation results.
+func EscapeDoubleQuotes(escape bool) TranslateOption {
+	return func(cfg *translateConfig) {
+		cfg.escapeDoubleQuotes = escape
+	}
+}
+
+// WithGlossary makes the Translator mask every occurrence of a Glossary
+// term before a segment is dispatched to the Service, and restores it
+// afterwards, substituting g's forced translation for targetLang if one is
+// set. It generalizes Preserve to a list of terms with optional per-language
+// forced translations.
+func WithGlossary(g Glossary) TranslateOption {
+	return func(cfg *translateConfig) {
+		cfg.glossary = g
+	}
+}
+
+// TranslationMemory caches previously translated segments, keyed by
+// (sourceLang, targetLang, sourceText), so that repeated runs over mostly
+// unchanged documents don't re-translate everything through the upstream
+// Service.
+type TranslationMemory interface {
+	// Lookup returns the cached translation of source, if any.
+	Lookup(sourceLang, targetLang, source string) (string, bool)
+	// Store records the translation of source as target.
+	Store(sourceLang, targetLang, source, target string)
+}
+
+// WithTranslationMemory makes the Translator consult mem before dispatching
+// a segment to the Service, and populates it with new translations as they
+// come in.
+func WithTranslationMemory(mem TranslationMemory) TranslateOption {
+	return func(cfg *translateConfig) {
+		cfg.memory = mem
+	}
+}
+
+// Translate translates the ranges that ranger extracts from input, from
+// sourceLang into targetLang, and returns the translated document.
+func (t *Translator) Translate(
+	ctx context.Context,
+	input io.Reader,
+	sourceLang, targetLang string,
+	ranger text.Ranger,
+	opts ...TranslateOption,
+) ([]byte, error) {
+	cfg := translateConfig{parallel: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	doc, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, fmt.Errorf("read input: %w", err)
+	}
+
+	rangeChan, errChan := ranger.Ranges(ctx, bytes.NewReader(doc))
+
+	var ranges []text.Range
+	for rangeChan != nil || errChan != nil {
+		select {
+		case r, ok := <-rangeChan:
+			if !ok {
+				rangeChan = nil
+				continue
+			}
+			ranges = append(ranges, r)
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("determine ranges: %w", err)
+			}
+		}
+	}
+
+	replacements := make([]text.Replacement, len(ranges))
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, cfg.parallel)
+		firstErr error
+	)
+
+	for i, r := range ranges {
+		i, r := i, r
+
+		source, err := text.Extract(bytes.NewReader(doc), r)
+		if err != nil {
+			return nil, fmt.Errorf("extract range %v: %w", r, err)
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			translated, err := t.translateSegment(ctx, source, sourceLang, targetLang, cfg)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("translate %q: %w", source, err)
+				}
+				return
+			}
+			if cfg.escapeDoubleQuotes {
+				translated = strings.ReplaceAll(translated, `"`, `\"`)
+			}
+			replacements[i] = text.Replacement{Range: r, Text: translated}
+		}()
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	out, err := text.ReplaceMany(string(doc), replacements...)
+	if err != nil {
+		return nil, fmt.Errorf("replace translations: %w", err)
+	}
+
+	return []byte(out), nil
+}
+
+func (t *Translator) translateSegment(ctx context.Context, source, sourceLang, targetLang string, cfg translateConfig) (string, error) {
+	glossary := cfg.glossaryWithPreserve()
+	masked, matches := glossary.Mask(source)
+
+	if cfg.memory != nil {
+		if cached, ok := cfg.memory.Lookup(sourceLang, targetLang, normalizeForMemory(masked)); ok {
+			return glossary.Unmask(cached, targetLang, matches), nil
+		}
+	}
+
+	translated, err := t.svc.Translate(ctx, masked, sourceLang, targetLang)
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.memory != nil {
+		cfg.memory.Store(sourceLang, targetLang, normalizeForMemory(masked), translated)
+	}
+
+	return glossary.Unmask(translated, targetLang, matches), nil
+}
+
+// glossaryWithPreserve returns cfg.glossary with an extra, unnamed Term for
+// cfg.preserve (if set), so that Preserve()'d substrings go through the same
+// mask-before-dispatch/unmask-after pipeline as Glossary terms, instead of
+// being sent to the Service untouched.
+func (cfg translateConfig) glossaryWithPreserve() Glossary {
+	if cfg.preserve == nil {
+		return cfg.glossary
+	}
+
+	g := cfg.glossary
+	g.Terms = append(append([]Term{}, g.Terms...), Term{Pattern: cfg.preserve})
+	return g
+}
+
+// normalizeForMemory collapses whitespace so that memory lookups are
+// insensitive to formatting noise. Glossary terms (including a Preserve()
+// pattern, see glossaryWithPreserve) are already replaced by stable
+// sentinel tokens in source by the time this is called, so their exact
+// values don't affect the cache key.
+func normalizeForMemory(source string) string {
+	return strings.Join(strings.Fields(source), " ")
+}
@@ -0,0 +1,117 @@
+package dragoman
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/dragoman/text"
+)
+
+// fullRanger yields a single range covering the entire input.
+type fullRanger struct{}
+
+func (fullRanger) Ranges(ctx context.Context, r io.Reader) (<-chan text.Range, <-chan error) {
+	ranges := make(chan text.Range, 1)
+	errs := make(chan error)
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		close(ranges)
+		go func() { errs <- err; close(errs) }()
+		return ranges, errs
+	}
+
+	ranges <- text.Range{0, uint(len(data))}
+	close(ranges)
+	close(errs)
+	return ranges, errs
+}
+
+// recordingService returns the source text unchanged, prefixed with
+// targetLang, and records every text it was asked to translate.
+type recordingService struct {
+	calls []string
+}
+
+func (svc *recordingService) Translate(ctx context.Context, text, sourceLang, targetLang string) (string, error) {
+	svc.calls = append(svc.calls, text)
+	return targetLang + ":" + text, nil
+}
+
+// fakeMemory is an in-memory dragoman.TranslationMemory for tests.
+type fakeMemory struct {
+	entries map[string]string
+	stores  int
+}
+
+func (mem *fakeMemory) Lookup(sourceLang, targetLang, source string) (string, bool) {
+	v, ok := mem.entries[sourceLang+"\x00"+targetLang+"\x00"+source]
+	return v, ok
+}
+
+func (mem *fakeMemory) Store(sourceLang, targetLang, source, target string) {
+	if mem.entries == nil {
+		mem.entries = make(map[string]string)
+	}
+	mem.entries[sourceLang+"\x00"+targetLang+"\x00"+source] = target
+	mem.stores++
+}
+
+func TestTranslateWithTranslationMemorySkipsServiceOnCacheHit(t *testing.T) {
+	svc := &recordingService{}
+	tr := New(svc)
+	mem := &fakeMemory{}
+
+	for i := 0; i < 2; i++ {
+		_, err := tr.Translate(
+			context.Background(),
+			strings.NewReader("hello world"),
+			"en", "de",
+			fullRanger{},
+			WithTranslationMemory(mem),
+		)
+		if err != nil {
+			t.Fatalf("Translate: %v", err)
+		}
+	}
+
+	if len(svc.calls) != 1 {
+		t.Fatalf("expected 1 call to the Service, got %d (the second Translate should have been served from memory)", len(svc.calls))
+	}
+	if mem.stores != 1 {
+		t.Fatalf("expected Store to be called once, got %d", mem.stores)
+	}
+}
+
+func TestTranslatePreservesSubstring(t *testing.T) {
+	svc := &recordingService{}
+	tr := New(svc)
+
+	expr := regexp.MustCompile(`%s`)
+	out, err := tr.Translate(
+		context.Background(),
+		strings.NewReader("hello %s world"),
+		"en", "de",
+		fullRanger{},
+		Preserve(expr),
+	)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+
+	if len(svc.calls) != 1 {
+		t.Fatalf("expected 1 call to the Service, got %d", len(svc.calls))
+	}
+	if svc.calls[0] == "hello %s world" {
+		t.Fatalf("preserved substring was sent to the Service unmasked: %q", svc.calls[0])
+	}
+
+	want := "de:hello %s world"
+	if string(out) != want {
+		t.Fatalf("Translate() = %q, want %q", out, want)
+	}
+}
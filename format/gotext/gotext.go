@@ -0,0 +1,349 @@
+// Package gotext provides a dragoman format for the gotext.json message
+// catalog layout produced by golang.org/x/text/message/pipeline (the
+// `gotext extract` / `gotext update` commands).
+package gotext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/bounoable/dragoman"
+	"github.com/bounoable/dragoman/cli"
+	"github.com/bounoable/dragoman/text"
+)
+
+// Catalog is the top-level structure of a gotext.json message catalog.
+type Catalog struct {
+	Language string    `json:"language"`
+	Messages []Message `json:"messages"`
+}
+
+// Message is a single entry of a Catalog.
+type Message struct {
+	ID           string        `json:"id"`
+	Message      string        `json:"message"`
+	Translation  string        `json:"translation"`
+	Placeholders []Placeholder `json:"placeholders,omitempty"`
+}
+
+// Placeholder describes a substitution token within a Message (e.g.
+// "%[1]s", "{Name}", "${x}") that must never be translated.
+type Placeholder struct {
+	ID             string `json:"id"`
+	String         string `json:"string"`
+	Type           string `json:"type,omitempty"`
+	UnderlyingType string `json:"underlyingType,omitempty"`
+	ArgNum         int    `json:"argNum,omitempty"`
+	Expr           string `json:"expr,omitempty"`
+}
+
+// NewRanger returns a text.Ranger that extracts the translatable content of
+// a gotext.json message catalog.
+//
+// For every message, the Ranger yields ranges over the "translation" field,
+// first split around any ICU plural/select/selectordinal arms with
+// text.SplitICU so that only their translatable leaves are sent off, then
+// split again around the message's placeholders so that dragoman.Translate
+// never sees placeholder tokens. The "message" field itself is left
+// untouched; only "translation" is overwritten.
+//
+// `gotext extract` leaves "translation" as an empty string, which has no
+// content to target a translated write into. Run Preprocess over the
+// catalog first (CLIFormat already wires this up) so that every message's
+// "translation" is seeded with its "message" before the Ranger ever sees it.
+func NewRanger() text.Ranger {
+	return ranger{}
+}
+
+type ranger struct{}
+
+func (ranger) Ranges(ctx context.Context, r io.Reader) (<-chan text.Range, <-chan error) {
+	ranges := make(chan text.Range)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(ranges)
+		defer close(errs)
+
+		input, err := ioutil.ReadAll(r)
+		if err != nil {
+			errs <- fmt.Errorf("read input: %w", err)
+			return
+		}
+
+		var cat Catalog
+		if err := json.Unmarshal(input, &cat); err != nil {
+			errs <- fmt.Errorf("unmarshal catalog: %w", err)
+			return
+		}
+
+		fields, err := fieldRanges(input)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		for i, msg := range cat.Messages {
+			if i >= len(fields) {
+				errs <- fmt.Errorf("message %q: no matching translation field found", msg.ID)
+				return
+			}
+
+			icuRanges, err := text.SplitICU(string(input), fields[i])
+			if err != nil {
+				errs <- fmt.Errorf("message %q: split ICU: %w", msg.ID, err)
+				return
+			}
+
+			for _, icuRange := range icuRanges {
+				for _, rg := range splitPlaceholders(input, icuRange, msg.Placeholders) {
+					select {
+					case <-ctx.Done():
+						errs <- ctx.Err()
+						return
+					case ranges <- rg:
+					}
+				}
+			}
+		}
+	}()
+
+	return ranges, errs
+}
+
+// fieldRanges walks input and returns, in document order, the byte range of
+// every message's "translation" string value (falling back to "message" if
+// "translation" is absent), excluding the surrounding quotes.
+func fieldRanges(input []byte) ([]text.Range, error) {
+	dec := json.NewDecoder(bytes.NewReader(input))
+
+	var ranges []text.Range
+	var lastMessage, lastTranslation text.Range
+	haveMessage, haveTranslation := false, false
+	var lastKey string
+
+	flush := func() {
+		if haveTranslation {
+			ranges = append(ranges, lastTranslation)
+		} else if haveMessage {
+			ranges = append(ranges, lastMessage)
+		}
+		haveMessage, haveTranslation = false, false
+	}
+
+	for {
+		before := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode token: %w", err)
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			if v == '}' {
+				flush()
+			}
+		case string:
+			after := dec.InputOffset()
+			if lastKey == "" {
+				lastKey = v
+				continue
+			}
+
+			switch lastKey {
+			case "message":
+				lastMessage = stringRange(input, before, after)
+				haveMessage = true
+			case "translation":
+				lastTranslation = stringRange(input, before, after)
+				haveTranslation = true
+			}
+			lastKey = ""
+			continue
+		}
+
+		lastKey = ""
+	}
+
+	return ranges, nil
+}
+
+// stringRange converts the decoder offsets that bracket a JSON string token
+// (including its quotes) into a text.Range over the quoted content.
+func stringRange(input []byte, before, after int64) text.Range {
+	start := before
+	for start < after && input[start] != '"' {
+		start++
+	}
+	return text.Range{uint(start + 1), uint(after - 1)}
+}
+
+// splitPlaceholders splits field (the byte range of a message's translatable
+// content) into sub-ranges that exclude every placeholder's literal string,
+// so that dragoman never translates, reorders or drops them.
+func splitPlaceholders(input []byte, field text.Range, placeholders []Placeholder) []text.Range {
+	if len(placeholders) == 0 {
+		return []text.Range{field}
+	}
+
+	raw := input[field[0]:field[1]]
+	var ranges []text.Range
+	pos := 0
+
+	for pos < len(raw) {
+		next, tokLen := nextPlaceholder(string(raw[pos:]), placeholders)
+		if next < 0 {
+			if pos < len(raw) {
+				ranges = append(ranges, text.Range{field[0] + uint(pos), field[1]})
+			}
+			break
+		}
+
+		if next > 0 {
+			ranges = append(ranges, text.Range{field[0] + uint(pos), field[0] + uint(pos+next)})
+		}
+		pos += next + tokLen
+	}
+
+	return ranges
+}
+
+// nextPlaceholder returns the offset and length of the closest placeholder
+// occurrence in s, or -1 if none of the placeholders occur in s.
+func nextPlaceholder(s string, placeholders []Placeholder) (offset, length int) {
+	offset = -1
+	for _, ph := range placeholders {
+		if ph.String == "" {
+			continue
+		}
+		if idx := strings.Index(s, ph.String); idx >= 0 && (offset == -1 || idx < offset) {
+			offset = idx
+			length = len(ph.String)
+		}
+	}
+	return offset, length
+}
+
+// PreservePattern builds a regular expression that matches every
+// placeholder string used in cat, for use as a dragoman.Preserve() option.
+// It acts as a safety net in addition to the range splitting that
+// NewRanger() already performs.
+func PreservePattern(cat Catalog) (*regexp.Regexp, error) {
+	seen := map[string]bool{}
+	var parts []string
+
+	for _, msg := range cat.Messages {
+		for _, ph := range msg.Placeholders {
+			if ph.String == "" || seen[ph.String] {
+				continue
+			}
+			seen[ph.String] = true
+			parts = append(parts, regexp.QuoteMeta(ph.String))
+		}
+	}
+
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	return regexp.Compile(strings.Join(parts, "|"))
+}
+
+// Options derives a dragoman.Preserve() option from the union of all
+// placeholder strings in content's catalog, for use as cli.Format.Options.
+// It acts as a safety net in addition to the range splitting that
+// NewRanger() already performs.
+func Options(content []byte) ([]dragoman.TranslateOption, error) {
+	var cat Catalog
+	if err := json.Unmarshal(content, &cat); err != nil {
+		return nil, fmt.Errorf("unmarshal catalog: %w", err)
+	}
+
+	expr, err := PreservePattern(cat)
+	if err != nil {
+		return nil, fmt.Errorf("build preserve pattern: %w", err)
+	}
+	if expr == nil {
+		return nil, nil
+	}
+
+	return []dragoman.TranslateOption{dragoman.Preserve(expr)}, nil
+}
+
+// Preprocess unmarshals content and seeds every message's empty
+// "translation" field with its "message", then re-encodes the catalog. For
+// use as cli.Format.Preprocess.
+//
+// `gotext extract` writes new messages with "translation" left as "", which
+// NewRanger can't target: there's nothing at that position to translate.
+// Seeding it with "message" first gives the Ranger real content to read and
+// a real (non-empty) range to overwrite, so the translated text lands in
+// "translation" instead of being silently skipped or clobbering "message".
+func Preprocess(content []byte) ([]byte, error) {
+	var cat Catalog
+	if err := json.Unmarshal(content, &cat); err != nil {
+		return nil, fmt.Errorf("unmarshal catalog: %w", err)
+	}
+
+	for i, msg := range cat.Messages {
+		if msg.Translation == "" {
+			cat.Messages[i].Translation = msg.Message
+		}
+	}
+
+	out, err := json.MarshalIndent(cat, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal catalog: %w", err)
+	}
+	return out, nil
+}
+
+// PostProcess unmarshals translated (the catalog with every message's
+// "translation" field already rewritten by the Ranger) and re-encodes it
+// via WriteBack, so that the catalog's top-level "language" tag ends up
+// set to targetLang instead of staying at the source language. For use as
+// cli.Format.PostProcess.
+func PostProcess(original, translated []byte, targetLang string) ([]byte, error) {
+	var cat Catalog
+	if err := json.Unmarshal(translated, &cat); err != nil {
+		return nil, fmt.Errorf("unmarshal translated catalog: %w", err)
+	}
+	return WriteBack(cat, targetLang)
+}
+
+// CLIFormat returns the cli.Format for registering gotext with a CLI,
+// e.g. cli.New(version, cli.WithFormat(gotext.CLIFormat())).
+func CLIFormat() cli.Format {
+	return cli.Format{
+		Name:  "gotext",
+		Ext:   ".gotext.json",
+		Short: "Translate gotext.json message catalogs",
+		Ranger: func() (text.Ranger, error) {
+			return NewRanger(), nil
+		},
+		Options:     Options,
+		Preprocess:  Preprocess,
+		PostProcess: PostProcess,
+	}
+}
+
+// WriteBack re-encodes cat as gotext.json, after setting the catalog's
+// Language to targetLang. The caller is expected to have already populated
+// each Message's Translation field with the translated text.
+func WriteBack(cat Catalog, targetLang string) ([]byte, error) {
+	cat.Language = targetLang
+	out, err := json.MarshalIndent(cat, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal catalog: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,291 @@
+package gotext
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/dragoman/text"
+)
+
+const sampleCatalog = `{
+    "language": "en",
+    "messages": [
+        {
+            "id": "greeting",
+            "message": "Hello %[1]s, you have {Count} new messages",
+            "translation": "Hello %[1]s, you have {Count} new messages",
+            "placeholders": [
+                {"id": "Arg_1", "string": "%[1]s"},
+                {"id": "Count", "string": "{Count}"}
+            ]
+        }
+    ]
+}`
+
+func TestRangesExcludePlaceholders(t *testing.T) {
+	rg := NewRanger()
+
+	rangeChan, errChan := rg.Ranges(context.Background(), strings.NewReader(sampleCatalog))
+
+	var ranges []text.Range
+	for rangeChan != nil || errChan != nil {
+		select {
+		case r, ok := <-rangeChan:
+			if !ok {
+				rangeChan = nil
+				continue
+			}
+			ranges = append(ranges, r)
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Ranges: %v", err)
+			}
+		}
+	}
+
+	for _, r := range ranges {
+		segment, err := text.Extract(strings.NewReader(sampleCatalog), r)
+		if err != nil {
+			t.Fatalf("extract range %v: %v", r, err)
+		}
+		if strings.Contains(segment, "%[1]s") || strings.Contains(segment, "{Count}") {
+			t.Fatalf("range %v leaked a placeholder: %q", r, segment)
+		}
+	}
+
+	if len(ranges) == 0 {
+		t.Fatal("expected at least one range")
+	}
+}
+
+func TestRangesOverEmptyTranslationAfterPreprocess(t *testing.T) {
+	const extracted = `{"language": "en", "messages": [{"id": "greeting", "message": "hi", "translation": ""}]}`
+
+	seeded, err := Preprocess([]byte(extracted))
+	if err != nil {
+		t.Fatalf("Preprocess: %v", err)
+	}
+
+	rg := NewRanger()
+	rangeChan, errChan := rg.Ranges(context.Background(), strings.NewReader(string(seeded)))
+
+	var ranges []text.Range
+	for rangeChan != nil || errChan != nil {
+		select {
+		case r, ok := <-rangeChan:
+			if !ok {
+				rangeChan = nil
+				continue
+			}
+			ranges = append(ranges, r)
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Ranges: %v", err)
+			}
+		}
+	}
+
+	if len(ranges) != 1 {
+		t.Fatalf("got %d ranges, want 1", len(ranges))
+	}
+
+	out, err := text.ReplaceMany(string(seeded), text.Replacement{Range: ranges[0], Text: "hallo"})
+	if err != nil {
+		t.Fatalf("ReplaceMany: %v", err)
+	}
+
+	var cat Catalog
+	if err := json.Unmarshal([]byte(out), &cat); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if cat.Messages[0].Translation != "hallo" {
+		t.Fatalf("Translation = %q, want %q", cat.Messages[0].Translation, "hallo")
+	}
+	if cat.Messages[0].Message != "hi" {
+		t.Fatalf("Message = %q, want unchanged %q", cat.Messages[0].Message, "hi")
+	}
+}
+
+const pluralCatalog = `{
+    "language": "en",
+    "messages": [
+        {
+            "id": "items",
+            "message": "{Count, plural, one {# item} other {# items}}",
+            "translation": "{Count, plural, one {# item} other {# items}}"
+        }
+    ]
+}`
+
+func TestRangesSplitICUPluralArms(t *testing.T) {
+	rg := NewRanger()
+
+	rangeChan, errChan := rg.Ranges(context.Background(), strings.NewReader(pluralCatalog))
+
+	var segments []string
+	for rangeChan != nil || errChan != nil {
+		select {
+		case r, ok := <-rangeChan:
+			if !ok {
+				rangeChan = nil
+				continue
+			}
+			segment, err := text.Extract(strings.NewReader(pluralCatalog), r)
+			if err != nil {
+				t.Fatalf("extract range %v: %v", r, err)
+			}
+			segments = append(segments, segment)
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Ranges: %v", err)
+			}
+		}
+	}
+
+	want := []string{" item", " items"}
+	if len(segments) != len(want) {
+		t.Fatalf("got %d segments %q, want %d segments %q", len(segments), segments, len(want), want)
+	}
+	for i, s := range segments {
+		if s != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, s, want[i])
+		}
+	}
+}
+
+const contractionPluralCatalog = `{
+    "language": "en",
+    "messages": [
+        {
+            "id": "items",
+            "message": "You don't have {Count, plural, one {# item} other {# items}} left.",
+            "translation": "You don't have {Count, plural, one {# item} other {# items}} left."
+        }
+    ]
+}`
+
+func TestRangesSplitICUPluralArmsAfterContraction(t *testing.T) {
+	rg := NewRanger()
+
+	rangeChan, errChan := rg.Ranges(context.Background(), strings.NewReader(contractionPluralCatalog))
+
+	var segments []string
+	for rangeChan != nil || errChan != nil {
+		select {
+		case r, ok := <-rangeChan:
+			if !ok {
+				rangeChan = nil
+				continue
+			}
+			segment, err := text.Extract(strings.NewReader(contractionPluralCatalog), r)
+			if err != nil {
+				t.Fatalf("extract range %v: %v", r, err)
+			}
+			segments = append(segments, segment)
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Ranges: %v", err)
+			}
+		}
+	}
+
+	want := []string{"You don't have ", " item", " items", " left."}
+	if len(segments) != len(want) {
+		t.Fatalf("got %d segments %q, want %d segments %q", len(segments), segments, len(want), want)
+	}
+	for i, s := range segments {
+		if s != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, s, want[i])
+		}
+	}
+}
+
+func TestOptionsPreservesPlaceholders(t *testing.T) {
+	opts, err := Options([]byte(sampleCatalog))
+	if err != nil {
+		t.Fatalf("Options: %v", err)
+	}
+	if len(opts) != 1 {
+		t.Fatalf("expected 1 option, got %d", len(opts))
+	}
+}
+
+func TestOptionsWithoutPlaceholders(t *testing.T) {
+	const cat = `{"language": "en", "messages": [{"id": "a", "message": "hi", "translation": "hi"}]}`
+
+	opts, err := Options([]byte(cat))
+	if err != nil {
+		t.Fatalf("Options: %v", err)
+	}
+	if len(opts) != 0 {
+		t.Fatalf("expected no options for a catalog without placeholders, got %d", len(opts))
+	}
+}
+
+func TestPreprocessSeedsEmptyTranslation(t *testing.T) {
+	const extracted = `{"language": "en", "messages": [{"id": "greeting", "message": "hi", "translation": ""}]}`
+
+	out, err := Preprocess([]byte(extracted))
+	if err != nil {
+		t.Fatalf("Preprocess: %v", err)
+	}
+
+	var cat Catalog
+	if err := json.Unmarshal(out, &cat); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if cat.Messages[0].Translation != "hi" {
+		t.Fatalf("Translation = %q, want %q", cat.Messages[0].Translation, "hi")
+	}
+}
+
+func TestPreprocessLeavesExistingTranslation(t *testing.T) {
+	out, err := Preprocess([]byte(sampleCatalog))
+	if err != nil {
+		t.Fatalf("Preprocess: %v", err)
+	}
+
+	var cat Catalog
+	if err := json.Unmarshal(out, &cat); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	want := "Hello %[1]s, you have {Count} new messages"
+	if cat.Messages[0].Translation != want {
+		t.Fatalf("Translation = %q, want %q", cat.Messages[0].Translation, want)
+	}
+}
+
+func TestPostProcessSetsLanguage(t *testing.T) {
+	translated := `{"language": "en", "messages": [{"id": "greeting", "message": "hi", "translation": "hallo"}]}`
+
+	out, err := PostProcess([]byte(sampleCatalog), []byte(translated), "de")
+	if err != nil {
+		t.Fatalf("PostProcess: %v", err)
+	}
+
+	var cat Catalog
+	if err := json.Unmarshal(out, &cat); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if cat.Language != "de" {
+		t.Fatalf("Language = %q, want %q", cat.Language, "de")
+	}
+}
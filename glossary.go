@@ -0,0 +1,249 @@
+package dragoman
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sentinel delimiters wrap the index of a Glossary match inside masked
+// text, e.g. "3" for the 4th match. They sit in a Unicode
+// private-use area, so they never collide with real document content.
+const (
+	sentinelStart = ''
+	sentinelEnd   = ''
+)
+
+var sentinelPattern = regexp.MustCompile(string(sentinelStart) + `(\d+)` + string(sentinelEnd))
+
+// Glossary is a set of do-not-translate terms and per-language forced
+// translations, applied to every segment before it's sent to the
+// translation Service, and restored afterwards.
+//
+// It generalizes the single Preserve() regular expression into a list of
+// Terms, each of which can carry its own case sensitivity and forced
+// translations, in addition to today's plain regex rules.
+type Glossary struct {
+	Terms []Term
+}
+
+// Term is a single Glossary entry.
+type Term struct {
+	// Text is the term to match in the source text. Ignored if Pattern is set.
+	Text string
+	// CaseInsensitive matches Text regardless of case. Has no effect if
+	// Pattern is set.
+	CaseInsensitive bool
+	// Pattern optionally matches the term via a regular expression instead
+	// of the literal Text.
+	Pattern *regexp.Regexp
+	// Translations maps a target language to the term's forced
+	// translation. If the target language isn't present, the term is left
+	// untranslated, the same as a plain do-not-translate term.
+	Translations map[string]string
+}
+
+// Match is an occurrence of a Term, found by Glossary.Mask.
+type Match struct {
+	Term Term
+	Text string
+}
+
+func (g Glossary) empty() bool {
+	return len(g.Terms) == 0
+}
+
+func (t Term) regexp() *regexp.Regexp {
+	if t.Pattern != nil {
+		return t.Pattern
+	}
+	if t.Text == "" {
+		return nil
+	}
+
+	pattern := regexp.QuoteMeta(t.Text)
+	if t.CaseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	expr, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return expr
+}
+
+// Mask replaces every occurrence of a Glossary term in source with a
+// stable sentinel token, so that the translation Service can't split,
+// reorder or translate it. The returned matches must be passed to Unmask
+// once the translation comes back.
+func (g Glossary) Mask(source string) (string, []Match) {
+	if g.empty() {
+		return source, nil
+	}
+
+	var matches []Match
+	masked := source
+
+	for _, term := range g.Terms {
+		expr := term.regexp()
+		if expr == nil {
+			continue
+		}
+
+		masked = expr.ReplaceAllStringFunc(masked, func(m string) string {
+			idx := len(matches)
+			matches = append(matches, Match{Term: term, Text: m})
+			return fmt.Sprintf("%c%d%c", sentinelStart, idx, sentinelEnd)
+		})
+	}
+
+	return masked, matches
+}
+
+// Unmask restores the sentinel tokens that Mask introduced into
+// translated. A term with a forced translation for targetLang is replaced
+// by that translation; every other term is restored to its original
+// source text.
+func (g Glossary) Unmask(translated, targetLang string, matches []Match) string {
+	if len(matches) == 0 {
+		return translated
+	}
+
+	return sentinelPattern.ReplaceAllStringFunc(translated, func(tok string) string {
+		sub := sentinelPattern.FindStringSubmatch(tok)
+		idx, err := strconv.Atoi(sub[1])
+		if err != nil || idx < 0 || idx >= len(matches) {
+			return tok
+		}
+
+		match := matches[idx]
+		if forced, ok := match.Term.Translations[targetLang]; ok {
+			return forced
+		}
+		return match.Text
+	})
+}
+
+// LoadGlossaryFile loads a Glossary from a YAML (.yaml/.yml) or CSV (.csv)
+// file at path, based on its extension.
+func LoadGlossaryFile(path string) (Glossary, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return LoadGlossaryYAML(path)
+	case ".csv":
+		return LoadGlossaryCSV(path)
+	default:
+		return Glossary{}, fmt.Errorf("unsupported glossary format %q", ext)
+	}
+}
+
+// glossaryYAML is the on-disk shape of a YAML glossary file:
+//
+//	terms:
+//	  - text: Dragoman
+//	  - text: login
+//	    caseInsensitive: true
+//	    translations:
+//	      de: Anmeldung
+//	  - pattern: '\bID-\d+\b'
+type glossaryYAML struct {
+	Terms []struct {
+		Text            string            `yaml:"text"`
+		Pattern         string            `yaml:"pattern"`
+		CaseInsensitive bool              `yaml:"caseInsensitive"`
+		Translations    map[string]string `yaml:"translations"`
+	} `yaml:"terms"`
+}
+
+// LoadGlossaryYAML loads a Glossary from a YAML file at path.
+func LoadGlossaryYAML(path string) (Glossary, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Glossary{}, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var doc glossaryYAML
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Glossary{}, fmt.Errorf("unmarshal %s: %w", path, err)
+	}
+
+	var g Glossary
+	for _, t := range doc.Terms {
+		term := Term{
+			Text:            t.Text,
+			CaseInsensitive: t.CaseInsensitive,
+			Translations:    t.Translations,
+		}
+
+		if t.Pattern != "" {
+			expr, err := regexp.Compile(t.Pattern)
+			if err != nil {
+				return Glossary{}, fmt.Errorf("compile pattern %q: %w", t.Pattern, err)
+			}
+			term.Pattern = expr
+		}
+
+		g.Terms = append(g.Terms, term)
+	}
+
+	return g, nil
+}
+
+// LoadGlossaryCSV loads a Glossary from a CSV file at path. Each row has
+// the form:
+//
+//	term,caseInsensitive,lang1:translation1;lang2:translation2
+//
+// The last two columns are optional; rows starting with "#" are ignored as
+// comments.
+func LoadGlossaryCSV(path string) (Glossary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Glossary{}, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return Glossary{}, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var g Glossary
+	for _, row := range rows {
+		if len(row) == 0 || strings.HasPrefix(strings.TrimSpace(row[0]), "#") {
+			continue
+		}
+
+		term := Term{Text: strings.TrimSpace(row[0])}
+
+		if len(row) > 1 && row[1] != "" {
+			term.CaseInsensitive, _ = strconv.ParseBool(strings.TrimSpace(row[1]))
+		}
+
+		if len(row) > 2 && row[2] != "" {
+			term.Translations = make(map[string]string)
+			for _, pair := range strings.Split(row[2], ";") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				term.Translations[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+		}
+
+		g.Terms = append(g.Terms, term)
+	}
+
+	return g, nil
+}
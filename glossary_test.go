@@ -0,0 +1,124 @@
+package dragoman
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestGlossaryMaskUnmaskRoundTrip(t *testing.T) {
+	g := Glossary{Terms: []Term{
+		{Text: "Dragoman"},
+		{Text: "login", CaseInsensitive: true, Translations: map[string]string{"de": "Anmeldung"}},
+	}}
+
+	masked, matches := g.Mask("Dragoman needs your Login details.")
+	if masked == "Dragoman needs your Login details." {
+		t.Fatal("Mask did not replace any terms")
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+
+	// Pretend the Service translated the masked text verbatim.
+	restored := g.Unmask(masked, "fr", matches)
+	if restored != "Dragoman needs your Login details." {
+		t.Fatalf("Unmask (no forced translation) = %q, want original text back", restored)
+	}
+
+	translated := g.Unmask(masked, "de", matches)
+	if want := "Dragoman needs your Anmeldung details."; translated != want {
+		t.Fatalf("Unmask (forced translation) = %q, want %q", translated, want)
+	}
+}
+
+func TestGlossaryEmptyIsNoop(t *testing.T) {
+	var g Glossary
+
+	masked, matches := g.Mask("nothing to see here")
+	if masked != "nothing to see here" || matches != nil {
+		t.Fatalf("empty Glossary should not alter the input, got %q, %v", masked, matches)
+	}
+}
+
+func TestGlossaryPattern(t *testing.T) {
+	g := Glossary{Terms: []Term{
+		{Pattern: regexp.MustCompile(`\bID-\d+\b`)}},
+	}
+
+	masked, matches := g.Mask("see ticket ID-42 and ID-43")
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+
+	restored := g.Unmask(masked, "de", matches)
+	if want := "see ticket ID-42 and ID-43"; restored != want {
+		t.Fatalf("Unmask() = %q, want %q", restored, want)
+	}
+}
+
+func TestLoadGlossaryYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "glossary.yaml")
+	content := `terms:
+  - text: Dragoman
+  - text: login
+    caseInsensitive: true
+    translations:
+      de: Anmeldung
+  - pattern: '\bID-\d+\b'
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write glossary file: %v", err)
+	}
+
+	g, err := LoadGlossaryFile(path)
+	if err != nil {
+		t.Fatalf("LoadGlossaryFile: %v", err)
+	}
+	if len(g.Terms) != 3 {
+		t.Fatalf("expected 3 terms, got %d", len(g.Terms))
+	}
+	if g.Terms[1].Translations["de"] != "Anmeldung" {
+		t.Fatalf("expected forced translation for %q, got %v", "login", g.Terms[1].Translations)
+	}
+	if g.Terms[2].Pattern == nil || !g.Terms[2].Pattern.MatchString("ID-7") {
+		t.Fatalf("expected term 3 to be a pattern matching ID-7")
+	}
+}
+
+func TestLoadGlossaryCSV(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "glossary.csv")
+	content := "# comment\nDragoman\nlogin,true,de:Anmeldung;fr:Connexion\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write glossary file: %v", err)
+	}
+
+	g, err := LoadGlossaryFile(path)
+	if err != nil {
+		t.Fatalf("LoadGlossaryFile: %v", err)
+	}
+	if len(g.Terms) != 2 {
+		t.Fatalf("expected 2 terms, got %d", len(g.Terms))
+	}
+	if !g.Terms[1].CaseInsensitive {
+		t.Fatal("expected the login term to be case-insensitive")
+	}
+	if g.Terms[1].Translations["fr"] != "Connexion" {
+		t.Fatalf("expected a French forced translation, got %v", g.Terms[1].Translations)
+	}
+}
+
+func TestLoadGlossaryFileUnsupportedExtension(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "glossary.txt")
+	if err := ioutil.WriteFile(path, []byte("Dragoman"), 0o644); err != nil {
+		t.Fatalf("write glossary file: %v", err)
+	}
+
+	if _, err := LoadGlossaryFile(path); err == nil {
+		t.Fatal("expected an error for an unsupported glossary extension")
+	}
+}
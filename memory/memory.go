@@ -0,0 +1,94 @@
+// Package memory provides file-backed dragoman.TranslationMemory
+// implementations.
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// key identifies a cached translation.
+type key struct {
+	SourceLang string
+	TargetLang string
+	Source     string
+}
+
+func (k key) string() string {
+	return k.SourceLang + "\x00" + k.TargetLang + "\x00" + k.Source
+}
+
+// JSONFile is a dragoman.TranslationMemory that persists entries to a JSON
+// file on disk.
+//
+// It is safe for concurrent use.
+type JSONFile struct {
+	path string
+
+	mux     sync.Mutex
+	entries map[string]string
+	dirty   bool
+}
+
+// OpenJSONFile loads a JSONFile-backed translation memory from path. If the
+// file doesn't exist yet, an empty memory is returned and the file is
+// created on the first Store().
+func OpenJSONFile(path string) (*JSONFile, error) {
+	mem := &JSONFile{path: path, entries: make(map[string]string)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return mem, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &mem.entries); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", path, err)
+	}
+
+	return mem, nil
+}
+
+// Lookup implements dragoman.TranslationMemory.
+func (mem *JSONFile) Lookup(sourceLang, targetLang, source string) (string, bool) {
+	mem.mux.Lock()
+	defer mem.mux.Unlock()
+	target, ok := mem.entries[key{sourceLang, targetLang, source}.string()]
+	return target, ok
+}
+
+// Store implements dragoman.TranslationMemory.
+func (mem *JSONFile) Store(sourceLang, targetLang, source, target string) {
+	mem.mux.Lock()
+	defer mem.mux.Unlock()
+	mem.entries[key{sourceLang, targetLang, source}.string()] = target
+	mem.dirty = true
+}
+
+// Flush persists pending entries to disk. Callers should Flush after a
+// translation run completes.
+func (mem *JSONFile) Flush() error {
+	mem.mux.Lock()
+	defer mem.mux.Unlock()
+
+	if !mem.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(mem.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal entries: %w", err)
+	}
+
+	if err := ioutil.WriteFile(mem.path, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", mem.path, err)
+	}
+
+	mem.dirty = false
+	return nil
+}
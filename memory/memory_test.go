@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONFileRoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tm.json")
+
+	mem, err := OpenJSONFile(path)
+	if err != nil {
+		t.Fatalf("OpenJSONFile: %v", err)
+	}
+
+	if _, ok := mem.Lookup("en", "de", "hello"); ok {
+		t.Fatal("expected no cached entry before Store")
+	}
+
+	mem.Store("en", "de", "hello", "hallo")
+
+	if got, ok := mem.Lookup("en", "de", "hello"); !ok || got != "hallo" {
+		t.Fatalf("Lookup() = (%q, %v), want (%q, true)", got, ok, "hallo")
+	}
+
+	if err := mem.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	reopened, err := OpenJSONFile(path)
+	if err != nil {
+		t.Fatalf("OpenJSONFile (reopen): %v", err)
+	}
+
+	got, ok := reopened.Lookup("en", "de", "hello")
+	if !ok || got != "hallo" {
+		t.Fatalf("after reopen: Lookup() = (%q, %v), want (%q, true)", got, ok, "hallo")
+	}
+}
+
+func TestOpenJSONFileMissingFileIsEmpty(t *testing.T) {
+	mem, err := OpenJSONFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("OpenJSONFile: %v", err)
+	}
+	if _, ok := mem.Lookup("en", "de", "hello"); ok {
+		t.Fatal("expected no entries for a memory opened from a nonexistent file")
+	}
+}
+
+func TestFlushIsNoopWithoutStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tm.json")
+
+	mem, err := OpenJSONFile(path)
+	if err != nil {
+		t.Fatalf("OpenJSONFile: %v", err)
+	}
+	if err := mem.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("expected Flush to not create a file when nothing was stored")
+	}
+}
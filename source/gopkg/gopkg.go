@@ -0,0 +1,466 @@
+// Package gopkg provides a dragoman Source and text.Ranger that extracts
+// translatable string literals from Go source files, for call sites such as
+// the golang.org/x/text/message Printer.Sprintf/Printf/Fprintf family.
+package gopkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bounoable/dragoman/cli"
+	"github.com/bounoable/dragoman/text"
+	"github.com/spf13/pflag"
+	"golang.org/x/tools/go/packages"
+)
+
+// CallSite identifies a function or method whose string-literal argument
+// should be extracted for translation.
+type CallSite struct {
+	// Qualifier is the receiver or package identifier as it appears at the
+	// call site (e.g. "p" for a *message.Printer variable named p, or "i18n"
+	// for a package-level i18n.T(...) call). Empty matches any qualifier.
+	Qualifier string
+	// Func is the method or function name (e.g. "Sprintf", "T").
+	Func string
+	// ArgIndex is the zero-based index of the string literal argument.
+	ArgIndex int
+}
+
+// DefaultCallSites are extracted when no call sites are configured: the
+// golang.org/x/text/message Printer.Sprintf/Printf/Fprintf family.
+func DefaultCallSites() []CallSite {
+	return []CallSite{
+		{Func: "Sprintf", ArgIndex: 0},
+		{Func: "Printf", ArgIndex: 0},
+		{Func: "Fprintf", ArgIndex: 1},
+	}
+}
+
+// ParseCallSites parses user-supplied call site specs of the form
+// "QUALIFIER.FUNC" (e.g. "i18n.T", "translate.Msg") into CallSites, with
+// the string literal expected as the first argument.
+func ParseCallSites(specs []string) ([]CallSite, error) {
+	calls := make([]CallSite, len(specs))
+	for i, spec := range specs {
+		parts := strings.SplitN(spec, ".", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid call site %q, expected QUALIFIER.FUNC", spec)
+		}
+		calls[i] = CallSite{Qualifier: parts[0], Func: parts[1], ArgIndex: 0}
+	}
+	return calls, nil
+}
+
+// NewRanger returns a text.Ranger that extracts the string literal
+// arguments of calls from Go source code, splitting each literal around its
+// fmt verbs (%s, %[1]d, %v, ...) and Go template placeholders ({{.Name}})
+// so that they are never sent to the translation Service.
+//
+// NewRanger parses a single file in isolation (go/parser, no type-checking,
+// no import resolution), which is enough for the one-file-at-a-time
+// dragoman.Translator/text.Ranger pipeline. For extracting a whole package
+// at once, with full type information and cross-file/import resolution,
+// use ExtractPackage instead.
+func NewRanger(calls []CallSite) text.Ranger {
+	if len(calls) == 0 {
+		calls = DefaultCallSites()
+	}
+	return ranger{calls: calls}
+}
+
+type ranger struct {
+	calls []CallSite
+}
+
+func (rg ranger) Ranges(ctx context.Context, r io.Reader) (<-chan text.Range, <-chan error) {
+	ranges := make(chan text.Range)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(ranges)
+		defer close(errs)
+
+		src, err := ioutil.ReadAll(r)
+		if err != nil {
+			errs <- fmt.Errorf("read input: %w", err)
+			return
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "", src, 0)
+		if err != nil {
+			errs <- fmt.Errorf("parse go source: %w", err)
+			return
+		}
+
+		var walkErr error
+		ast.Inspect(file, func(n ast.Node) bool {
+			if walkErr != nil {
+				return false
+			}
+
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			site, ok := matchCallSite(rg.calls, sel)
+			if !ok || site.ArgIndex >= len(call.Args) {
+				return true
+			}
+
+			lit, ok := call.Args[site.ArgIndex].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+
+			// Exclude the surrounding quotes.
+			start := fset.Position(lit.Pos()).Offset + 1
+			end := fset.Position(lit.End()).Offset - 1
+
+			for _, field := range splitVerbs(src, text.Range{uint(start), uint(end)}) {
+				select {
+				case <-ctx.Done():
+					walkErr = ctx.Err()
+					return false
+				case ranges <- field:
+				}
+			}
+
+			return true
+		})
+
+		if walkErr != nil {
+			errs <- walkErr
+		}
+	}()
+
+	return ranges, errs
+}
+
+func matchCallSite(calls []CallSite, sel *ast.SelectorExpr) (CallSite, bool) {
+	var qualifier string
+	if ident, ok := sel.X.(*ast.Ident); ok {
+		qualifier = ident.Name
+	}
+
+	for _, c := range calls {
+		if c.Func != sel.Sel.Name {
+			continue
+		}
+		if c.Qualifier != "" && c.Qualifier != qualifier {
+			continue
+		}
+		return c, true
+	}
+
+	return CallSite{}, false
+}
+
+// verbPattern matches fmt-style verbs (%s, %[1]d, %v, %%) and Go template
+// actions ({{.Name}}), which must be preserved verbatim.
+var verbPattern = regexp.MustCompile(`%\[?[0-9]*\]?[-+ #0]*[0-9]*\.?[0-9]*[a-zA-Z%]|\{\{[^{}]*\}\}`)
+
+// splitVerbs splits field into sub-ranges that exclude its fmt verbs and Go
+// template placeholders.
+func splitVerbs(src []byte, field text.Range) []text.Range {
+	raw := src[field[0]:field[1]]
+	matches := verbPattern.FindAllIndex(raw, -1)
+	if len(matches) == 0 {
+		return []text.Range{field}
+	}
+
+	var ranges []text.Range
+	pos := 0
+	for _, m := range matches {
+		if m[0] > pos {
+			ranges = append(ranges, text.Range{field[0] + uint(pos), field[0] + uint(m[0])})
+		}
+		pos = m[1]
+	}
+	if pos < len(raw) {
+		ranges = append(ranges, text.Range{field[0] + uint(pos), field[1]})
+	}
+
+	return ranges
+}
+
+// ExtractPackage loads the Go packages under dir, recursively, with
+// golang.org/x/tools/go/packages (resolving imports and build tags the same
+// way `go build` would, and type-checking every file of each package, not
+// just one in isolation), and returns the translatable string literal
+// ranges of every call site matched by calls (DefaultCallSites() if empty),
+// keyed by the absolute path of the file they occur in. Each file's ranges
+// are already split around fmt verbs and Go template placeholders, same as
+// NewRanger's ranger.
+func ExtractPackage(dir string, calls []CallSite) (map[string][]text.Range, error) {
+	files, err := extractPackageFiles(dir, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]text.Range, len(files))
+	for path, f := range files {
+		result[path] = f.ranges
+	}
+	return result, nil
+}
+
+// packageFile pairs a package file's content with its translatable ranges,
+// as found by extractPackageFiles.
+type packageFile struct {
+	content []byte
+	ranges  []text.Range
+}
+
+// extractPackageFiles is the shared implementation behind ExtractPackage and
+// NewPackageRanger: it loads every package under dir exactly once and keeps
+// each file's content alongside the ranges computed from it, so callers
+// never need to re-read a file (and risk it changing) to match content back
+// up with its ranges.
+func extractPackageFiles(dir string, calls []CallSite) (map[string]packageFile, error) {
+	if len(calls) == 0 {
+		calls = DefaultCallSites()
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedSyntax | packages.NeedTypes |
+			packages.NeedTypesInfo | packages.NeedCompiledGoFiles | packages.NeedImports,
+		Dir: dir,
+	}
+
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("load packages at %s: %w", dir, err)
+	}
+	if n := packages.PrintErrors(pkgs); n > 0 {
+		return nil, fmt.Errorf("packages at %s have %d error(s)", dir, n)
+	}
+
+	result := make(map[string]packageFile)
+
+	for _, pkg := range pkgs {
+		for i, file := range pkg.Syntax {
+			path := pkg.CompiledGoFiles[i]
+
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", path, err)
+			}
+
+			var ranges []text.Range
+			ast.Inspect(file, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+
+				site, ok := matchCallSite(calls, sel)
+				if !ok || site.ArgIndex >= len(call.Args) {
+					return true
+				}
+
+				lit, ok := call.Args[site.ArgIndex].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					return true
+				}
+
+				// Only accept calls that actually resolved during type
+				// checking, so a shadowed identifier or an unrelated
+				// method of the same name doesn't produce a false match.
+				_, isSelection := pkg.TypesInfo.Selections[sel]
+				_, isQualifiedIdent := pkg.TypesInfo.Uses[sel.Sel]
+				if !isSelection && !isQualifiedIdent {
+					return true
+				}
+
+				start := pkg.Fset.Position(lit.Pos()).Offset + 1
+				end := pkg.Fset.Position(lit.End()).Offset - 1
+
+				ranges = append(ranges, splitVerbs(src, text.Range{uint(start), uint(end)})...)
+
+				return true
+			})
+
+			result[path] = packageFile{content: src, ranges: ranges}
+		}
+	}
+
+	return result, nil
+}
+
+// NewPackageRanger returns a text.Ranger like NewRanger, but backed by a
+// single whole-package-tree load via extractPackageFiles, so translatable
+// literals are found with full type information and cross-file import
+// resolution instead of one file parsed in isolation. It's meant for
+// directory-mode CLI translation, where the files under dir form one or
+// more packages.
+//
+// Files that extractPackageFiles didn't resolve as part of a package (e.g.
+// build-tag-excluded files, or _test.go files, which aren't loaded since
+// packages.Config.Tests isn't set) fall back to NewRanger's single-file,
+// no-type-info extraction, so they're still translated.
+func NewPackageRanger(dir string, calls []CallSite) (text.Ranger, error) {
+	if len(calls) == 0 {
+		calls = DefaultCallSites()
+	}
+
+	files, err := extractPackageFiles(dir, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	byContent := make(map[string][]text.Range, len(files))
+	for _, f := range files {
+		byContent[string(f.content)] = f.ranges
+	}
+
+	return packageRanger{byContent: byContent, fallback: NewRanger(calls)}, nil
+}
+
+// packageRanger serves the ranges that NewPackageRanger precomputed for a
+// whole package, matching each Ranges call's input against the file content
+// read while loading the package (the text.Ranger interface doesn't carry a
+// file path, only the content). Content that doesn't match any loaded file
+// is handled by fallback instead.
+type packageRanger struct {
+	byContent map[string][]text.Range
+	fallback  text.Ranger
+}
+
+func (rg packageRanger) Ranges(ctx context.Context, r io.Reader) (<-chan text.Range, <-chan error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		ranges := make(chan text.Range)
+		errs := make(chan error, 1)
+		close(ranges)
+		errs <- fmt.Errorf("read input: %w", err)
+		close(errs)
+		return ranges, errs
+	}
+
+	precomputed, ok := rg.byContent[string(src)]
+	if !ok {
+		return rg.fallback.Ranges(ctx, bytes.NewReader(src))
+	}
+
+	ranges := make(chan text.Range)
+	errs := make(chan error)
+	go func() {
+		defer close(ranges)
+		defer close(errs)
+		for _, rg := range precomputed {
+			select {
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			case ranges <- rg:
+			}
+		}
+	}()
+	return ranges, errs
+}
+
+// CLISource returns the cli.Source for registering Go source extraction
+// with a CLI, e.g. cli.New(version, cli.WithSource(gopkg.CLISource())).
+func CLISource() cli.Source {
+	return cli.Source{
+		Name: "go",
+		Reader: func(p string) (io.Reader, error) {
+			return os.Open(p)
+		},
+	}
+}
+
+// ShadowFileName returns the write-back output path for srcPath and
+// targetLang, e.g. "messages.go" + "de" -> "messages_de.go".
+func ShadowFileName(srcPath, targetLang string) string {
+	ext := filepath.Ext(srcPath)
+	return strings.TrimSuffix(srcPath, ext) + "_" + targetLang + ext
+}
+
+// WriteBack renders a shadow Go file containing the translated source,
+// guarded by a build tag named after targetLang so that it never shadows
+// the original file unless explicitly selected.
+func WriteBack(targetLang string, src []byte, replacements ...text.Replacement) ([]byte, error) {
+	translated, err := text.ReplaceMany(string(src), replacements...)
+	if err != nil {
+		return nil, fmt.Errorf("replace translations: %w", err)
+	}
+
+	return buildTagged(targetLang, []byte(translated)), nil
+}
+
+// buildTagged prepends a build tag named after targetLang to src, the same
+// guard WriteBack uses.
+func buildTagged(targetLang string, src []byte) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// +build %s\n\n", targetLang)
+	b.Write(src)
+	return []byte(b.String())
+}
+
+// CLIFormat returns the cli.Format for registering Go source string literal
+// extraction with a CLI, e.g. cli.New(version, cli.WithFormat(gopkg.CLIFormat())).
+//
+// It adds a --write-back flag: when set, the translation of each file is
+// written next to its source as a build-tagged shadow file (see WriteBack
+// and ShadowFileName) instead of being printed to stdout.
+func CLIFormat() cli.Format {
+	var writeBack bool
+
+	return cli.Format{
+		Name:  "go",
+		Ext:   ".go",
+		Short: "Translate Go source string literals",
+		Flags: func(fs *pflag.FlagSet) {
+			fs.BoolVar(&writeBack, "write-back", false, "Write the translation next to its source as a build-tagged shadow file (<file>_<lang>.go) instead of stdout")
+		},
+		Ranger: func() (text.Ranger, error) {
+			return NewRanger(nil), nil
+		},
+		DirRanger: func(dir string) (text.Ranger, error) {
+			// Fall back to the single-file, no-type-info Ranger if the
+			// directory doesn't load as a well-formed Go package tree (e.g.
+			// missing dependencies, a file mid-edit): directory translation
+			// shouldn't require the package to build when it didn't before.
+			rg, err := NewPackageRanger(dir, nil)
+			if err != nil {
+				return NewRanger(nil), nil
+			}
+			return rg, nil
+		},
+		PostProcess: func(original, translated []byte, targetLang string) ([]byte, error) {
+			if !writeBack {
+				return translated, nil
+			}
+			return buildTagged(targetLang, translated), nil
+		},
+		OutputPath: func(srcPath, targetLang string) string {
+			if !writeBack {
+				return ""
+			}
+			return ShadowFileName(srcPath, targetLang)
+		},
+	}
+}
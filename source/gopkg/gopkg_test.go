@@ -0,0 +1,207 @@
+package gopkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/dragoman/text"
+)
+
+const sampleSource = `package greet
+
+import "golang.org/x/text/message"
+
+func Hello(p *message.Printer, name string) string {
+	return p.Sprintf("Hello, %s! You have %[1]d new messages.", name)
+}
+`
+
+func collectRanges(t *testing.T, rg text.Ranger, src string) []string {
+	t.Helper()
+
+	rangeChan, errChan := rg.Ranges(context.Background(), strings.NewReader(src))
+
+	var segments []string
+	for rangeChan != nil || errChan != nil {
+		select {
+		case r, ok := <-rangeChan:
+			if !ok {
+				rangeChan = nil
+				continue
+			}
+			s, err := text.Extract(strings.NewReader(src), r)
+			if err != nil {
+				t.Fatalf("extract range %v: %v", r, err)
+			}
+			segments = append(segments, s)
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Ranges: %v", err)
+			}
+		}
+	}
+
+	return segments
+}
+
+func TestNewRangerDefaultCallSitesSplitsVerbs(t *testing.T) {
+	rg := NewRanger(nil)
+	got := collectRanges(t, rg, sampleSource)
+
+	want := []string{"Hello, ", "! You have ", " new messages."}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNewRangerIgnoresUnmatchedCallSites(t *testing.T) {
+	const src = `package greet
+
+func Hello(name string) string {
+	return "Hello, " + name
+}
+`
+	rg := NewRanger([]CallSite{{Func: "Sprintf", ArgIndex: 0}})
+	if got := collectRanges(t, rg, src); len(got) != 0 {
+		t.Fatalf("expected no ranges, got %q", got)
+	}
+}
+
+func TestNewPackageRangerExtractsTypeCheckedCallSites(t *testing.T) {
+	dir := t.TempDir()
+	const src = `package tempmod
+
+import "fmt"
+
+func Hello() string {
+	return fmt.Sprintf("Hello, %s!", "world")
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module tempmod\n\ngo 1.18\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rg, err := NewPackageRanger(dir, nil)
+	if err != nil {
+		t.Fatalf("NewPackageRanger: %v", err)
+	}
+
+	got := collectRanges(t, rg, src)
+	want := []string{"Hello, ", "!"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtractPackageRecursesIntoSubpackages(t *testing.T) {
+	dir := t.TempDir()
+	const src = `package sub
+
+import "fmt"
+
+func Hello() string {
+	return fmt.Sprintf("Hello, %s!", "world")
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module tempmod\n\ngo 1.18\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ExtractPackage(dir, nil)
+	if err != nil {
+		t.Fatalf("ExtractPackage: %v", err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("expected 1 file with ranges, got %d: %v", len(res), res)
+	}
+}
+
+func TestNewPackageRangerFallsBackForUnmatchedContent(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module tempmod\n\ngo 1.18\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package tempmod\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rg, err := NewPackageRanger(dir, nil)
+	if err != nil {
+		t.Fatalf("NewPackageRanger: %v", err)
+	}
+
+	got := collectRanges(t, rg, sampleSource)
+	want := []string{"Hello, ", "! You have ", " new messages."}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCLIFormatDirRangerFallsBackOnBrokenPackage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module tempmod\n\ngo 1.18\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A syntax error keeps the package from type-checking at all.
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package tempmod\n\nfunc Hello( {\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	format := CLIFormat()
+	rg, err := format.DirRanger(dir)
+	if err != nil {
+		t.Fatalf("DirRanger: %v", err)
+	}
+
+	got := collectRanges(t, rg, sampleSource)
+	want := []string{"Hello, ", "! You have ", " new messages."}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestShadowFileName(t *testing.T) {
+	got := ShadowFileName("messages.go", "de")
+	if want := "messages_de.go"; got != want {
+		t.Fatalf("ShadowFileName() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteBackAddsBuildTag(t *testing.T) {
+	out, err := WriteBack("de", []byte(`package greet`))
+	if err != nil {
+		t.Fatalf("WriteBack: %v", err)
+	}
+	if !strings.HasPrefix(string(out), "// +build de\n\n") {
+		t.Fatalf("WriteBack() = %q, want it to start with the de build tag", out)
+	}
+	if !strings.Contains(string(out), "package greet") {
+		t.Fatalf("WriteBack() = %q, want it to contain the source", out)
+	}
+}
@@ -111,6 +111,11 @@ func Replace(text, repl string, r Range) (string, error) {
 //		Replacement{Range: Range{0, 4}, Text: "Hi,"},
 //		Replacement{Range: Range{5, 7}, Text: "I am"},
 //	) = "Hi, I am a sentence."
+//
+// The ranges produced by SplitICU for the leaf arms of a plural/select
+// message are disjoint (they never overlap, since each arm occupies its own
+// section of the input), so they can be passed to ReplaceMany as-is,
+// interleaved with ranges from other parts of the same document.
 func ReplaceMany(input string, replacements ...Replacement) (string, error) {
 	type offset struct {
 		start  uint
@@ -151,3 +156,220 @@ type Replacement struct {
 	// Text is the replacement text.
 	Text string
 }
+
+// SplitICU parses the ICU MessageFormat skeleton within range r of input
+// (e.g. "You have {count, plural, one {# item} other {# items}}.") and
+// returns the sub-ranges that are actually translatable: the leaf arms of
+// every plural/select/selectordinal argument (the `{...}` body of each
+// case), plus the plain text surrounding them.
+//
+// The selector keyword ("plural"/"select"/"selectordinal"), the argument's
+// variable name, and the case labels ("one", "other", "female", ...) are
+// never included in the returned ranges. Inside a plural arm, the `#`
+// count placeholder is preserved: it's excluded from the returned ranges,
+// the same way a case label is. Simple/opaque arguments (e.g. `{var}` or
+// `{var, number}`) aren't translatable and are skipped entirely. Nested
+// plural/select arguments inside an arm are handled recursively.
+//
+// Text quoted per ICU rules (a '...' span) is treated as a literal run:
+// braces and `#` inside it aren't structural and the quotes themselves are
+// included in the surrounding translatable range.
+func SplitICU(input string, r Range) ([]Range, error) {
+	if r.Len() < 0 {
+		return nil, &RangeError{Range: r, Message: "negative length range"}
+	}
+	if int(r[1]) > len(input) {
+		return nil, &RangeError{Range: r, Message: fmt.Sprintf("range end (pos %d) after input end", r[1])}
+	}
+
+	return splitICUBody([]byte(input), int(r[0]), int(r[1]), false)
+}
+
+// splitICUBody returns the translatable sub-ranges of input[start:end]. If
+// maskHash is true, unescaped '#' runes are treated as a non-translatable
+// placeholder (used for plural arm bodies).
+func splitICUBody(input []byte, start, end int, maskHash bool) ([]Range, error) {
+	var ranges []Range
+	pos := start
+	textStart := start
+
+	flush := func(to int) {
+		if to > textStart {
+			ranges = append(ranges, Range{uint(textStart), uint(to)})
+		}
+	}
+
+	for pos < end {
+		switch {
+		case isICUQuoteStart(input, pos, end):
+			pos = skipICUQuote(input, pos, end)
+		case input[pos] == '{':
+			flush(pos)
+			argEnd, argRanges, err := parseICUArg(input, pos, end)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, argRanges...)
+			pos = argEnd
+			textStart = pos
+		case maskHash && input[pos] == '#':
+			flush(pos)
+			pos++
+			textStart = pos
+		default:
+			pos++
+		}
+	}
+	flush(end)
+
+	return ranges, nil
+}
+
+// parseICUArg parses the ICU argument starting at input[pos] (which must be
+// '{') and returns the offset right after its matching closing '}', plus
+// the translatable ranges of its arms (if it's a plural/select/
+// selectordinal argument).
+func parseICUArg(input []byte, pos, end int) (int, []Range, error) {
+	openBrace := pos
+	pos++ // consume '{'
+	pos = skipICUSpace(input, pos, end)
+
+	for pos < end && input[pos] != ',' && input[pos] != '}' {
+		pos++
+	}
+	if pos >= end {
+		return 0, nil, &RangeError{Message: "unterminated ICU argument"}
+	}
+	if input[pos] == '}' {
+		// A plain "{var}" substitution has nothing translatable inside it.
+		return pos + 1, nil, nil
+	}
+	pos++ // consume ','
+	pos = skipICUSpace(input, pos, end)
+
+	kwStart := pos
+	for pos < end && input[pos] != ',' && input[pos] != '}' {
+		pos++
+	}
+	keyword := strings.TrimSpace(string(input[kwStart:pos]))
+
+	if keyword != "plural" && keyword != "select" && keyword != "selectordinal" {
+		// An opaque argument, e.g. "{var, number}" or "{var, date, long}":
+		// nothing inside it is translatable.
+		closeIdx, err := matchICUBrace(input, openBrace, end)
+		if err != nil {
+			return 0, nil, err
+		}
+		return closeIdx + 1, nil, nil
+	}
+	if pos >= end || input[pos] != ',' {
+		return 0, nil, &RangeError{Message: "expected ',' after ICU argument type"}
+	}
+	pos++ // consume ','
+
+	var ranges []Range
+	for {
+		pos = skipICUSpace(input, pos, end)
+		if pos >= end {
+			return 0, nil, &RangeError{Message: "unterminated ICU argument"}
+		}
+		if input[pos] == '}' {
+			pos++
+			break
+		}
+
+		// Case label (e.g. "one", "=0", "offset:1").
+		for pos < end && input[pos] != '{' && !isICUSpace(input[pos]) {
+			pos++
+		}
+		pos = skipICUSpace(input, pos, end)
+		if pos >= end || input[pos] != '{' {
+			return 0, nil, &RangeError{Message: "expected '{' after ICU case label"}
+		}
+
+		bodyStart := pos + 1
+		bodyEnd, err := matchICUBrace(input, pos, end)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		armRanges, err := splitICUBody(input, bodyStart, bodyEnd, true)
+		if err != nil {
+			return 0, nil, err
+		}
+		ranges = append(ranges, armRanges...)
+
+		pos = bodyEnd + 1
+	}
+
+	return pos, ranges, nil
+}
+
+// matchICUBrace returns the offset of the '}' matching the '{' at
+// input[openIdx], accounting for nested braces and quoted literal runs.
+func matchICUBrace(input []byte, openIdx, end int) (int, error) {
+	depth := 0
+	pos := openIdx
+
+	for pos < end {
+		if isICUQuoteStart(input, pos, end) {
+			pos = skipICUQuote(input, pos, end)
+			continue
+		}
+		switch input[pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return pos, nil
+			}
+		}
+		pos++
+	}
+
+	return 0, &RangeError{Message: "unterminated ICU argument"}
+}
+
+// isICUQuoteStart reports whether input[pos] opens an ICU quoted literal
+// run. Per the ICU MessageFormat quoting rule, a '\'' only starts an escape
+// when it's immediately followed by a syntax character ('{', '}', '#', '|'
+// or another '\''); a bare apostrophe in running text (e.g. an English
+// contraction like "don't") is literal and must not be treated as a quote.
+func isICUQuoteStart(input []byte, pos, end int) bool {
+	if input[pos] != '\'' || pos+1 >= end {
+		return false
+	}
+	switch input[pos+1] {
+	case '{', '}', '#', '|', '\'':
+		return true
+	default:
+		return false
+	}
+}
+
+// skipICUQuote skips the quoted literal run starting at input[pos] (which
+// must open an ICU quote per isICUQuoteStart), returning the offset right
+// after its closing quote, or the end of input if the quote is never
+// closed.
+func skipICUQuote(input []byte, pos, end int) int {
+	pos++
+	for pos < end {
+		if input[pos] == '\'' {
+			return pos + 1
+		}
+		pos++
+	}
+	return pos
+}
+
+func skipICUSpace(input []byte, pos, end int) int {
+	for pos < end && isICUSpace(input[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func isICUSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
@@ -0,0 +1,106 @@
+package text
+
+import "testing"
+
+func extractAll(t *testing.T, input string, ranges []Range) []string {
+	t.Helper()
+	out := make([]string, len(ranges))
+	for i, r := range ranges {
+		s, err := ExtractString(input, r)
+		if err != nil {
+			t.Fatalf("ExtractString(%v): %v", r, err)
+		}
+		out[i] = s
+	}
+	return out
+}
+
+func TestSplitICUPlainText(t *testing.T) {
+	input := "hello world"
+	ranges, err := SplitICU(input, Range{0, uint(len(input))})
+	if err != nil {
+		t.Fatalf("SplitICU: %v", err)
+	}
+
+	got := extractAll(t, input, ranges)
+	want := []string{"hello world"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplitICUOpaqueArgument(t *testing.T) {
+	input := "you owe {amount, number}."
+	ranges, err := SplitICU(input, Range{0, uint(len(input))})
+	if err != nil {
+		t.Fatalf("SplitICU: %v", err)
+	}
+
+	got := extractAll(t, input, ranges)
+	want := []string{"you owe ", "."}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitICUPlural(t *testing.T) {
+	input := "You have {count, plural, one {# item} other {# items}}."
+	ranges, err := SplitICU(input, Range{0, uint(len(input))})
+	if err != nil {
+		t.Fatalf("SplitICU: %v", err)
+	}
+
+	got := extractAll(t, input, ranges)
+	want := []string{"You have ", " item", " items", "."}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitICUContractionBeforePlural(t *testing.T) {
+	input := "You don't have {count, plural, one {# item} other {# items}} left."
+	ranges, err := SplitICU(input, Range{0, uint(len(input))})
+	if err != nil {
+		t.Fatalf("SplitICU: %v", err)
+	}
+
+	got := extractAll(t, input, ranges)
+	want := []string{"You don't have ", " item", " items", " left."}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitICUNestedSelect(t *testing.T) {
+	input := "{gender, select, male {He} female {She} other {They}} liked this."
+	ranges, err := SplitICU(input, Range{0, uint(len(input))})
+	if err != nil {
+		t.Fatalf("SplitICU: %v", err)
+	}
+
+	got := extractAll(t, input, ranges)
+	want := []string{"He", "She", "They", " liked this."}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}